@@ -0,0 +1,55 @@
+// Package email sends transactional email over SMTP.
+package email
+
+import (
+	"fmt"
+	"net/mail"
+	"net/smtp"
+	"strings"
+)
+
+// Sender dispatches a single plain-text email. It is an interface so
+// tests can inject a fake instead of talking to a real SMTP server.
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPConfig holds the connection details for an SMTP relay.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type smtpSender struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPSender builds a Sender backed by net/smtp.
+func NewSMTPSender(cfg SMTPConfig) Sender {
+	return &smtpSender{cfg: cfg}
+}
+
+func (s *smtpSender) Send(to, subject, body string) error {
+	parsed, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("email: invalid recipient address: %w", err)
+	}
+	to = parsed.Address
+	subject = stripCRLF(subject)
+
+	addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
+	auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		s.cfg.From, to, subject, body)
+	return smtp.SendMail(addr, auth, s.cfg.From, []string{to}, []byte(msg))
+}
+
+// stripCRLF removes carriage returns and newlines so a value can't inject
+// extra headers into the raw SMTP message.
+func stripCRLF(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	return strings.ReplaceAll(s, "\n", "")
+}
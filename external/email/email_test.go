@@ -0,0 +1,36 @@
+package email
+
+import "testing"
+
+func TestFakeSender_RecordsMessage(t *testing.T) {
+	sender := NewFakeSender()
+
+	if err := sender.Send("user@example.com", "Reset your password", "body"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if len(sender.Messages) != 1 {
+		t.Fatalf("len(Messages) = %d, want 1", len(sender.Messages))
+	}
+	got := sender.Messages[0]
+	if got.To != "user@example.com" || got.Subject != "Reset your password" || got.Body != "body" {
+		t.Fatalf("Messages[0] = %+v, want To/Subject/Body to match what was sent", got)
+	}
+}
+
+func TestSMTPSender_Send_RejectsHeaderInjection(t *testing.T) {
+	s := &smtpSender{cfg: SMTPConfig{Host: "smtp.invalid", Port: 25, From: "noreply@example.com"}}
+
+	err := s.Send("user@example.com\r\nBcc:attacker@evil.com", "subject", "body")
+	if err == nil {
+		t.Fatal("Send with a CRLF-smuggling recipient = nil error, want an error")
+	}
+}
+
+func TestStripCRLF(t *testing.T) {
+	got := stripCRLF("Reset\r\nBcc: attacker@evil.com")
+	want := "ResetBcc: attacker@evil.com"
+	if got != want {
+		t.Fatalf("stripCRLF() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,29 @@
+package email
+
+import "sync"
+
+// Message is a single email captured by FakeSender.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// FakeSender is a Sender that records messages instead of dispatching
+// them over SMTP, for use in tests.
+type FakeSender struct {
+	mu       sync.Mutex
+	Messages []Message
+}
+
+// NewFakeSender builds an empty FakeSender.
+func NewFakeSender() *FakeSender {
+	return &FakeSender{}
+}
+
+func (s *FakeSender) Send(to, subject, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Messages = append(s.Messages, Message{To: to, Subject: subject, Body: body})
+	return nil
+}
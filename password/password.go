@@ -0,0 +1,137 @@
+// Package password hashes and verifies user passwords.
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidHash is returned when a stored hash isn't in a format Verify
+// recognizes.
+var ErrInvalidHash = errors.New("password: invalid hash format")
+
+// Hasher hashes new passwords and verifies them against a stored hash.
+// Verify reports needsRehash when the stored hash was produced by a
+// legacy or outdated scheme, so callers can transparently upgrade it in
+// place on the next successful login.
+type Hasher interface {
+	Hash(plain string) (string, error)
+	Verify(hash, plain string) (ok bool, needsRehash bool, err error)
+}
+
+// argon2Params are the cost parameters baked into every hash minted by
+// Default. Bumping any of these makes Verify flag existing hashes for
+// rehash the next time their owner logs in.
+type argon2Params struct {
+	time    uint32
+	memory  uint32 // KiB
+	threads uint8
+	saltLen uint32
+	keyLen  uint32
+}
+
+var currentParams = argon2Params{
+	time:    1,
+	memory:  64 * 1024,
+	threads: 4,
+	saltLen: 16,
+	keyLen:  32,
+}
+
+// legacyBcryptSalt is appended to the plaintext before the legacy bcrypt
+// scheme hashes it. It only exists to verify hashes minted before the
+// Argon2id migration; Hash never uses it.
+const legacyBcryptSalt = "dev-salt-change-me"
+
+type argon2idHasher struct {
+	params argon2Params
+}
+
+// Default is the password.Hasher used throughout the app.
+var Default Hasher = &argon2idHasher{params: currentParams}
+
+// Hash derives a fresh Argon2id hash for plain, encoded as a PHC string:
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+func (h *argon2idHasher) Hash(plain string) (string, error) {
+	salt := make([]byte, h.params.saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	sum := argon2.IDKey([]byte(plain), salt, h.params.time, h.params.memory, h.params.threads, h.params.keyLen)
+	return encodePHC(h.params, salt, sum), nil
+}
+
+// Verify checks plain against hash, whether it's a current Argon2id hash,
+// one minted with older Argon2id parameters, or a pre-migration bcrypt
+// hash. needsRehash is true whenever hash isn't already a current-params
+// Argon2id hash, so the caller can mint and persist a fresh one.
+func (h *argon2idHasher) Verify(hash, plain string) (bool, bool, error) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, sum, err := decodePHC(hash)
+		if err != nil {
+			return false, false, err
+		}
+		candidate := argon2.IDKey([]byte(plain), salt, params.time, params.memory, params.threads, uint32(len(sum)))
+		if subtle.ConstantTimeCompare(candidate, sum) != 1 {
+			return false, false, nil
+		}
+		return true, params != h.params, nil
+	}
+
+	if strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain+legacyBcryptSalt))
+		if err != nil {
+			return false, false, nil
+		}
+		return true, true, nil
+	}
+
+	return false, false, ErrInvalidHash
+}
+
+func encodePHC(p argon2Params, salt, sum []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(sum))
+}
+
+func decodePHC(hash string) (argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	// ["", "argon2id", "v=19", "m=...,t=...,p=...", "<salt>", "<hash>"]
+	if len(parts) != 6 {
+		return argon2Params{}, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return argon2Params{}, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return argon2Params{}, nil, nil, ErrInvalidHash
+	}
+
+	var p argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return argon2Params{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2Params{}, nil, nil, ErrInvalidHash
+	}
+	sum, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return argon2Params{}, nil, nil, ErrInvalidHash
+	}
+	p.saltLen = uint32(len(salt))
+	p.keyLen = uint32(len(sum))
+	return p, salt, sum, nil
+}
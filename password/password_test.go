@@ -0,0 +1,78 @@
+package password
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestArgon2idHasher_HashVerifyRoundTrip(t *testing.T) {
+	hash, err := Default.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	ok, needsRehash, err := Default.Verify(hash, "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !ok {
+		t.Fatal("Verify() ok = false, want true for the correct password")
+	}
+	if needsRehash {
+		t.Fatal("Verify() needsRehash = true for a hash minted with current params, want false")
+	}
+
+	ok, _, err = Default.Verify(hash, "wrong password")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if ok {
+		t.Fatal("Verify() ok = true for the wrong password, want false")
+	}
+}
+
+func TestArgon2idHasher_Verify_LegacyBcryptNeedsRehash(t *testing.T) {
+	plain := "correct horse battery staple"
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte(plain+legacyBcryptSalt), bcrypt.DefaultCost)
+	if err != nil {
+		t.Fatalf("bcrypt.GenerateFromPassword: %v", err)
+	}
+
+	ok, needsRehash, err := Default.Verify(string(legacyHash), plain)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !ok {
+		t.Fatal("Verify() ok = false for a valid legacy bcrypt hash, want true")
+	}
+	if !needsRehash {
+		t.Fatal("Verify() needsRehash = false for a legacy bcrypt hash, want true")
+	}
+
+	ok, _, err = Default.Verify(string(legacyHash), "wrong password")
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if ok {
+		t.Fatal("Verify() ok = true for the wrong password against a legacy hash, want false")
+	}
+}
+
+func TestArgon2idHasher_Verify_MalformedHash(t *testing.T) {
+	cases := []string{
+		"",
+		"not a hash at all",
+		"$argon2id$v=19$m=65536,t=1,p=4$onlyfourparts",
+		"$argon2id$v=1$m=65536,t=1,p=4$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=notanumber,t=1,p=4$c2FsdA$aGFzaA",
+		"$argon2id$v=19$m=65536,t=1,p=4$not-base64!$aGFzaA",
+	}
+	for _, hash := range cases {
+		_, _, err := Default.Verify(hash, "anything")
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("Verify(%q) error = %v, want ErrInvalidHash", hash, err)
+		}
+	}
+}
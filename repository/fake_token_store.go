@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// InMemoryTokenStore is an in-memory TokenStore, for use in tests in
+// place of a real Redis connection. It mirrors the expiry semantics of
+// redisTokenStore (a refresh token is gone once its data.Exp has passed)
+// without talking to Redis.
+type InMemoryTokenStore struct {
+	mu          sync.Mutex
+	refresh     map[string]RefreshTokenData
+	userTokens  map[string]map[string]struct{}
+	blacklisted map[string]time.Time
+}
+
+// NewInMemoryTokenStore builds an empty InMemoryTokenStore.
+func NewInMemoryTokenStore() *InMemoryTokenStore {
+	return &InMemoryTokenStore{
+		refresh:     make(map[string]RefreshTokenData),
+		userTokens:  make(map[string]map[string]struct{}),
+		blacklisted: make(map[string]time.Time),
+	}
+}
+
+func (s *InMemoryTokenStore) StoreRefreshToken(_ context.Context, jti string, data RefreshTokenData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh[jti] = data
+	if s.userTokens[data.UserID] == nil {
+		s.userTokens[data.UserID] = make(map[string]struct{})
+	}
+	s.userTokens[data.UserID][jti] = struct{}{}
+	return nil
+}
+
+func (s *InMemoryTokenStore) GetRefreshToken(_ context.Context, jti string) (RefreshTokenData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.refresh[jti]
+	if !ok || time.Now().After(data.Exp) {
+		return RefreshTokenData{}, ErrRefreshTokenNotFound
+	}
+	return data, nil
+}
+
+func (s *InMemoryTokenStore) DeleteRefreshToken(_ context.Context, jti string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.refresh, jti)
+	return nil
+}
+
+func (s *InMemoryTokenStore) DeleteAllRefreshTokensForUser(_ context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for jti := range s.userTokens[userID] {
+		delete(s.refresh, jti)
+	}
+	delete(s.userTokens, userID)
+	return nil
+}
+
+func (s *InMemoryTokenStore) BlacklistAccessToken(_ context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blacklisted[jti] = time.Now().Add(ttl)
+	return nil
+}
+
+func (s *InMemoryTokenStore) IsBlacklisted(_ context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exp, ok := s.blacklisted[jti]
+	if !ok {
+		return false, nil
+	}
+	if time.Now().After(exp) {
+		delete(s.blacklisted, jti)
+		return false, nil
+	}
+	return true, nil
+}
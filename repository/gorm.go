@@ -0,0 +1,14 @@
+package repository
+
+import "gorm.io/gorm"
+
+// GormDatabase wraps the process-wide gorm connection shared by every
+// repository.
+type GormDatabase struct {
+	*gorm.DB
+}
+
+// NewGormDatabase wraps an already-opened gorm connection.
+func NewGormDatabase(db *gorm.DB) *GormDatabase {
+	return &GormDatabase{DB: db}
+}
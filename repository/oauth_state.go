@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const oauthStateExpPeriod = 10 * time.Minute
+
+// OAuthStateStore tracks the `state` value handed out by an OAuth2
+// "start" endpoint so the matching callback can confirm it was not
+// forged and was redeemed by the same client.
+type OAuthStateStore interface {
+	// SaveState remembers state as bound to clientIP for a short TTL.
+	SaveState(ctx context.Context, state string, clientIP string) error
+	// ConsumeState reports whether state is still valid for clientIP,
+	// and deletes it either way so it cannot be replayed.
+	ConsumeState(ctx context.Context, state string, clientIP string) (bool, error)
+}
+
+type redisOAuthStateStore struct {
+	client *redis.Client
+}
+
+// NewRedisOAuthStateStore builds an OAuthStateStore backed by go-redis/v8.
+func NewRedisOAuthStateStore(client *redis.Client) OAuthStateStore {
+	return &redisOAuthStateStore{client: client}
+}
+
+func oauthStateKey(state string) string { return "oauth_state:" + state }
+
+func (s *redisOAuthStateStore) SaveState(ctx context.Context, state string, clientIP string) error {
+	return s.client.Set(ctx, oauthStateKey(state), clientIP, oauthStateExpPeriod).Err()
+}
+
+func (s *redisOAuthStateStore) ConsumeState(ctx context.Context, state string, clientIP string) (bool, error) {
+	storedIP, err := s.client.Get(ctx, oauthStateKey(state)).Result()
+	if err == redis.Nil {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if err := s.client.Del(ctx, oauthStateKey(state)).Err(); err != nil {
+		return false, err
+	}
+	return storedIP == clientIP, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RateLimiter enforces a token-bucket request cap per key (e.g.
+// "ip+email"), used to slow down enumeration/brute-force attempts.
+type RateLimiter interface {
+	// Allow reports whether another request under key is permitted,
+	// consuming one token from its bucket as a side effect. The bucket
+	// has capacity limit and refills continuously at limit tokens per
+	// window, so an empty bucket is full again after one window.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error)
+}
+
+type redisRateLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisRateLimiter builds a RateLimiter backed by go-redis/v8.
+func NewRedisRateLimiter(client *redis.Client) RateLimiter {
+	return &redisRateLimiter{client: client}
+}
+
+// tokenBucketScript atomically refills and drains a token bucket stored
+// as a Redis hash. Doing the refill/drain math in Lua keeps the
+// read-modify-write atomic without a WATCH/MULTI round trip.
+//
+// KEYS[1] - bucket key
+// ARGV[1] - capacity (== limit)
+// ARGV[2] - refill rate, tokens per second (== limit / window)
+// ARGV[3] - now, unix seconds as a float
+// ARGV[4] - key TTL in seconds, so idle buckets don't linger forever
+var tokenBucketScript = redis.NewScript(`
+local tokens = tonumber(redis.call("HGET", KEYS[1], "tokens"))
+local last = tonumber(redis.call("HGET", KEYS[1], "last"))
+local capacity = tonumber(ARGV[1])
+local refillRate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+if tokens == nil then
+  tokens = capacity
+  last = now
+end
+
+local elapsed = math.max(0, now - last)
+tokens = math.min(capacity, tokens + elapsed * refillRate)
+
+local allowed = 0
+if tokens >= 1 then
+  allowed = 1
+  tokens = tokens - 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tokens, "last", now)
+redis.call("EXPIRE", KEYS[1], ttl)
+return allowed
+`)
+
+func (r *redisRateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, error) {
+	refillRate := float64(limit) / window.Seconds()
+	now := float64(time.Now().UnixNano()) / 1e9
+	// Bound the bucket's idle TTL so an abandoned key doesn't outlive its
+	// usefulness, while still giving a fully-drained bucket time to refill.
+	ttl := int64(window.Seconds() * 2)
+	allowed, err := tokenBucketScript.Run(ctx, r.client, []string{"ratelimit:" + key}, limit, refillRate, now, ttl).Int()
+	if err != nil {
+		return false, err
+	}
+	return allowed == 1, nil
+}
@@ -0,0 +1,61 @@
+// Package schema holds the gorm row types persisted by the repository
+// package.
+package schema
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User is a registered account.
+type User struct {
+	ID       uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserName string    `gorm:"uniqueIndex"`
+	Password string
+	Gender   string
+	Age      int
+	Email    string
+	Phone    string
+	Address  string
+
+	// TwoFactorEnabled is true once the user has confirmed TOTP
+	// enrollment via /api/auth/2fa/verify.
+	TwoFactorEnabled bool
+	// TOTPSecret is the user's base32 TOTP secret, AES-GCM encrypted at
+	// rest.
+	TOTPSecret string
+
+	// OIDCSubject is the `sub` claim of the linked Google account, if the
+	// user has signed in with Google at least once. nil for accounts
+	// that only use a password. A pointer so multiple password-only
+	// users can coexist under a single NULL-excluding unique index.
+	OIDCSubject *string `gorm:"uniqueIndex"`
+}
+
+// RecoveryCode is a single-use 2FA bypass code. The plaintext code is
+// shown to the user once at enrollment; only its bcrypt hash is kept.
+type RecoveryCode struct {
+	ID       uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID   uuid.UUID `gorm:"type:uuid;index"`
+	CodeHash string
+	Used     bool
+}
+
+// PasswordReset is a single-use, time-limited password reset request.
+// TokenHash is the SHA-256 hash of the token emailed to the user; the
+// plaintext token is never persisted.
+type PasswordReset struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;index"`
+	TokenHash string    `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// UserRole grants userID a named role (e.g. "admin"), checked by the
+// requireRole middleware.
+type UserRole struct {
+	UserID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Role   string    `gorm:"primaryKey"`
+}
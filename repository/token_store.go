@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrRefreshTokenNotFound is returned when a refresh token has expired,
+// been revoked, or never existed.
+var ErrRefreshTokenNotFound = errors.New("repository: refresh token not found")
+
+// RefreshTokenData is the value stored under refresh:{jti}.
+type RefreshTokenData struct {
+	UserID string
+	// Roles records the roles granted to the user when the refresh token
+	// was issued. Rotation does not use it: refresh re-reads the roles
+	// table so grants and revocations take effect on the next refresh.
+	Roles []string
+	Exp   time.Time
+}
+
+// TokenStore tracks refresh tokens and blacklisted access tokens so both
+// can be revoked server-side (logout, rotation).
+type TokenStore interface {
+	// StoreRefreshToken saves token under refresh:{jti}, expiring at
+	// data.Exp.
+	StoreRefreshToken(ctx context.Context, jti string, data RefreshTokenData) error
+	// GetRefreshToken looks up a previously stored refresh token.
+	// Returns ErrRefreshTokenNotFound if it is missing or expired.
+	GetRefreshToken(ctx context.Context, jti string) (RefreshTokenData, error)
+	// DeleteRefreshToken revokes a refresh token, e.g. on logout or
+	// rotation.
+	DeleteRefreshToken(ctx context.Context, jti string) error
+	// DeleteAllRefreshTokensForUser revokes every refresh token issued to
+	// userID, e.g. after a password reset.
+	DeleteAllRefreshTokensForUser(ctx context.Context, userID string) error
+	// BlacklistAccessToken adds jti to blacklist:{jti} until ttl elapses,
+	// rejecting it from authenticate even though it has not expired yet.
+	BlacklistAccessToken(ctx context.Context, jti string, ttl time.Duration) error
+	// IsBlacklisted reports whether jti was blacklisted by logout.
+	IsBlacklisted(ctx context.Context, jti string) (bool, error)
+}
+
+type redisTokenStore struct {
+	client *redis.Client
+}
+
+// NewRedisTokenStore builds a TokenStore backed by go-redis/v8.
+func NewRedisTokenStore(client *redis.Client) TokenStore {
+	return &redisTokenStore{client: client}
+}
+
+func refreshKey(jti string) string         { return "refresh:" + jti }
+func blacklistKey(jti string) string       { return "blacklist:" + jti }
+func userSessionsKey(userID string) string { return "user_sessions:" + userID }
+
+func (s *redisTokenStore) StoreRefreshToken(ctx context.Context, jti string, data RefreshTokenData) error {
+	ttl := time.Until(data.Exp)
+	if ttl <= 0 {
+		return errors.New("repository: refresh token already expired")
+	}
+	pipe := s.client.TxPipeline()
+	pipe.HSet(ctx, refreshKey(jti), map[string]interface{}{
+		"userID": data.UserID,
+		"roles":  strings.Join(data.Roles, ","),
+		"exp":    data.Exp.Unix(),
+	})
+	pipe.Expire(ctx, refreshKey(jti), ttl)
+	pipe.SAdd(ctx, userSessionsKey(data.UserID), jti)
+	pipe.Expire(ctx, userSessionsKey(data.UserID), ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *redisTokenStore) GetRefreshToken(ctx context.Context, jti string) (RefreshTokenData, error) {
+	vals, err := s.client.HGetAll(ctx, refreshKey(jti)).Result()
+	if err != nil {
+		return RefreshTokenData{}, err
+	}
+	if len(vals) == 0 {
+		return RefreshTokenData{}, ErrRefreshTokenNotFound
+	}
+	expUnix, err := strconv.ParseInt(vals["exp"], 10, 64)
+	if err != nil {
+		return RefreshTokenData{}, err
+	}
+	exp := time.Unix(expUnix, 0)
+	if time.Now().After(exp) {
+		return RefreshTokenData{}, ErrRefreshTokenNotFound
+	}
+	var roles []string
+	if vals["roles"] != "" {
+		roles = strings.Split(vals["roles"], ",")
+	}
+	return RefreshTokenData{UserID: vals["userID"], Roles: roles, Exp: exp}, nil
+}
+
+func (s *redisTokenStore) DeleteRefreshToken(ctx context.Context, jti string) error {
+	return s.client.Del(ctx, refreshKey(jti)).Err()
+}
+
+func (s *redisTokenStore) DeleteAllRefreshTokensForUser(ctx context.Context, userID string) error {
+	jtis, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	if len(jtis) == 0 {
+		return nil
+	}
+	keys := make([]string, len(jtis))
+	for i, jti := range jtis {
+		keys[i] = refreshKey(jti)
+	}
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, keys...)
+	pipe.Del(ctx, userSessionsKey(userID))
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *redisTokenStore) BlacklistAccessToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(ctx, blacklistKey(jti), "1", ttl).Err()
+}
+
+func (s *redisTokenStore) IsBlacklisted(ctx context.Context, jti string) (bool, error) {
+	n, err := s.client.Exists(ctx, blacklistKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
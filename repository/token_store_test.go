@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInMemoryTokenStore_RoundTrip(t *testing.T) {
+	store := NewInMemoryTokenStore()
+	ctx := context.Background()
+	data := RefreshTokenData{UserID: "user-1", Roles: []string{"user"}, Exp: time.Now().Add(time.Hour)}
+
+	if err := store.StoreRefreshToken(ctx, "jti-1", data); err != nil {
+		t.Fatalf("StoreRefreshToken: %v", err)
+	}
+
+	got, err := store.GetRefreshToken(ctx, "jti-1")
+	if err != nil {
+		t.Fatalf("GetRefreshToken: %v", err)
+	}
+	if got.UserID != data.UserID || len(got.Roles) != 1 || got.Roles[0] != "user" {
+		t.Fatalf("GetRefreshToken returned %+v, want %+v", got, data)
+	}
+
+	if err := store.DeleteRefreshToken(ctx, "jti-1"); err != nil {
+		t.Fatalf("DeleteRefreshToken: %v", err)
+	}
+	if _, err := store.GetRefreshToken(ctx, "jti-1"); !errors.Is(err, ErrRefreshTokenNotFound) {
+		t.Fatalf("GetRefreshToken after delete = %v, want ErrRefreshTokenNotFound", err)
+	}
+}
+
+func TestInMemoryTokenStore_ExpiredTokenNotFound(t *testing.T) {
+	store := NewInMemoryTokenStore()
+	ctx := context.Background()
+	data := RefreshTokenData{UserID: "user-1", Exp: time.Now().Add(-time.Minute)}
+
+	if err := store.StoreRefreshToken(ctx, "jti-expired", data); err != nil {
+		t.Fatalf("StoreRefreshToken: %v", err)
+	}
+
+	if _, err := store.GetRefreshToken(ctx, "jti-expired"); !errors.Is(err, ErrRefreshTokenNotFound) {
+		t.Fatalf("GetRefreshToken for an expired token = %v, want ErrRefreshTokenNotFound", err)
+	}
+}
+
+func TestInMemoryTokenStore_DeleteAllRefreshTokensForUser(t *testing.T) {
+	store := NewInMemoryTokenStore()
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour)
+
+	if err := store.StoreRefreshToken(ctx, "jti-a", RefreshTokenData{UserID: "user-1", Exp: exp}); err != nil {
+		t.Fatalf("StoreRefreshToken: %v", err)
+	}
+	if err := store.StoreRefreshToken(ctx, "jti-b", RefreshTokenData{UserID: "user-1", Exp: exp}); err != nil {
+		t.Fatalf("StoreRefreshToken: %v", err)
+	}
+
+	if err := store.DeleteAllRefreshTokensForUser(ctx, "user-1"); err != nil {
+		t.Fatalf("DeleteAllRefreshTokensForUser: %v", err)
+	}
+
+	for _, jti := range []string{"jti-a", "jti-b"} {
+		if _, err := store.GetRefreshToken(ctx, jti); !errors.Is(err, ErrRefreshTokenNotFound) {
+			t.Fatalf("GetRefreshToken(%q) after revoke = %v, want ErrRefreshTokenNotFound", jti, err)
+		}
+	}
+}
+
+func TestInMemoryTokenStore_Blacklist(t *testing.T) {
+	store := NewInMemoryTokenStore()
+	ctx := context.Background()
+
+	if blacklisted, err := store.IsBlacklisted(ctx, "jti-1"); err != nil || blacklisted {
+		t.Fatalf("IsBlacklisted before blacklisting = %v, %v, want false, nil", blacklisted, err)
+	}
+
+	if err := store.BlacklistAccessToken(ctx, "jti-1", time.Minute); err != nil {
+		t.Fatalf("BlacklistAccessToken: %v", err)
+	}
+	if blacklisted, err := store.IsBlacklisted(ctx, "jti-1"); err != nil || !blacklisted {
+		t.Fatalf("IsBlacklisted after blacklisting = %v, %v, want true, nil", blacklisted, err)
+	}
+}
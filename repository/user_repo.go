@@ -0,0 +1,175 @@
+package repository
+
+import (
+	schema "Recommendation-System/repository/schema"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserRepo persists and looks up user accounts.
+type UserRepo interface {
+	SelectUserByUsername(username string) (schema.User, error)
+	SelectUserByID(id string) (schema.User, error)
+	InsertUser(user schema.User) error
+
+	// EnrollTwoFactor saves the (encrypted) TOTP secret and the bcrypt
+	// hashes of freshly generated recovery codes for userID. Two-factor
+	// stays disabled until ConfirmTwoFactor is called.
+	EnrollTwoFactor(userID string, encryptedSecret string, recoveryCodes []schema.RecoveryCode) error
+	// ConfirmTwoFactor flips TwoFactorEnabled on once enrollment has been
+	// verified.
+	ConfirmTwoFactor(userID string) error
+	// SelectRecoveryCodes returns the unused recovery codes for userID.
+	SelectRecoveryCodes(userID string) ([]schema.RecoveryCode, error)
+	// MarkRecoveryCodeUsed consumes a recovery code so it cannot be
+	// reused.
+	MarkRecoveryCodeUsed(codeID uuid.UUID) error
+
+	// SelectUserByEmail looks up a user for password-reset purposes.
+	SelectUserByEmail(email string) (schema.User, error)
+	// InsertPasswordReset saves a new reset request.
+	InsertPasswordReset(reset schema.PasswordReset) error
+	// SelectPasswordResetByTokenHash looks up a still-unused reset
+	// request by the SHA-256 hash of its plaintext token.
+	SelectPasswordResetByTokenHash(tokenHash string) (schema.PasswordReset, error)
+	// MarkPasswordResetUsed consumes a reset request so its token cannot
+	// be replayed.
+	MarkPasswordResetUsed(id uuid.UUID) error
+	// UpdateUserPassword overwrites userID's stored password hash.
+	UpdateUserPassword(userID string, passwordHash string) error
+
+	// SelectUserByOIDCSubject looks up a user previously linked to a
+	// Google account by its `sub` claim.
+	SelectUserByOIDCSubject(sub string) (schema.User, error)
+	// LinkOIDCSubject backfills OIDCSubject on an existing password
+	// account that has now signed in with a matching Google email.
+	LinkOIDCSubject(userID string, sub string) error
+
+	// SelectRolesForUser returns the role names granted to userID.
+	SelectRolesForUser(userID string) ([]string, error)
+	// GrantRole adds role to userID; a no-op if already granted.
+	GrantRole(userID string, role string) error
+	// RevokeRole removes role from userID.
+	RevokeRole(userID string, role string) error
+}
+
+type userRepo struct {
+	db *GormDatabase
+}
+
+// NewUserRepo builds a gorm-backed UserRepo.
+func NewUserRepo(db *GormDatabase) UserRepo {
+	return &userRepo{db: db}
+}
+
+func (r *userRepo) SelectUserByUsername(username string) (schema.User, error) {
+	user := schema.User{}
+	err := r.db.Where("user_name = ?", username).First(&user).Error
+	return user, err
+}
+
+func (r *userRepo) SelectUserByID(id string) (schema.User, error) {
+	user := schema.User{}
+	err := r.db.Where("id = ?", id).First(&user).Error
+	return user, err
+}
+
+func (r *userRepo) InsertUser(user schema.User) error {
+	return r.db.Create(&user).Error
+}
+
+func (r *userRepo) EnrollTwoFactor(userID string, encryptedSecret string, recoveryCodes []schema.RecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&schema.User{}).Where("id = ?", userID).
+			Update("totp_secret", encryptedSecret).Error; err != nil {
+			return err
+		}
+		// Re-enrolling must invalidate any recovery codes issued under a
+		// previous secret, or they'd stay valid forever and bypass 2FA
+		// set up under the new one.
+		if err := tx.Where("user_id = ?", userID).Delete(&schema.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&recoveryCodes).Error
+	})
+}
+
+func (r *userRepo) ConfirmTwoFactor(userID string) error {
+	return r.db.Model(&schema.User{}).Where("id = ?", userID).
+		Update("two_factor_enabled", true).Error
+}
+
+func (r *userRepo) SelectRecoveryCodes(userID string) ([]schema.RecoveryCode, error) {
+	var codes []schema.RecoveryCode
+	err := r.db.Where("user_id = ? AND used = ?", userID, false).Find(&codes).Error
+	return codes, err
+}
+
+func (r *userRepo) MarkRecoveryCodeUsed(codeID uuid.UUID) error {
+	return r.db.Model(&schema.RecoveryCode{}).Where("id = ?", codeID).
+		Update("used", true).Error
+}
+
+func (r *userRepo) SelectUserByEmail(email string) (schema.User, error) {
+	user := schema.User{}
+	err := r.db.Where("email = ?", email).First(&user).Error
+	return user, err
+}
+
+func (r *userRepo) InsertPasswordReset(reset schema.PasswordReset) error {
+	return r.db.Create(&reset).Error
+}
+
+func (r *userRepo) SelectPasswordResetByTokenHash(tokenHash string) (schema.PasswordReset, error) {
+	reset := schema.PasswordReset{}
+	err := r.db.Where("token_hash = ? AND used = ?", tokenHash, false).First(&reset).Error
+	return reset, err
+}
+
+func (r *userRepo) MarkPasswordResetUsed(id uuid.UUID) error {
+	return r.db.Model(&schema.PasswordReset{}).Where("id = ?", id).
+		Update("used", true).Error
+}
+
+func (r *userRepo) UpdateUserPassword(userID string, passwordHash string) error {
+	return r.db.Model(&schema.User{}).Where("id = ?", userID).
+		Update("password", passwordHash).Error
+}
+
+func (r *userRepo) SelectUserByOIDCSubject(sub string) (schema.User, error) {
+	user := schema.User{}
+	err := r.db.Where("oidc_subject = ?", sub).First(&user).Error
+	return user, err
+}
+
+func (r *userRepo) LinkOIDCSubject(userID string, sub string) error {
+	return r.db.Model(&schema.User{}).Where("id = ?", userID).
+		Update("oidc_subject", sub).Error
+}
+
+func (r *userRepo) SelectRolesForUser(userID string) ([]string, error) {
+	var userRoles []schema.UserRole
+	if err := r.db.Where("user_id = ?", userID).Find(&userRoles).Error; err != nil {
+		return nil, err
+	}
+	roles := make([]string, len(userRoles))
+	for i, ur := range userRoles {
+		roles[i] = ur.Role
+	}
+	return roles, nil
+}
+
+func (r *userRepo) GrantRole(userID string, role string) error {
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&schema.UserRole{UserID: id, Role: role}).Error
+}
+
+func (r *userRepo) RevokeRole(userID string, role string) error {
+	return r.db.Where("user_id = ? AND role = ?", userID, role).Delete(&schema.UserRole{}).Error
+}
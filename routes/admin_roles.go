@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type updateUserRolesReq struct {
+	Role   string `json:"role" binding:"required"`
+	Action string `json:"action" binding:"required"` // "grant" or "revoke"
+}
+
+// updateUserRoles grants or revokes a role on the user identified by the
+// :id path param. Gated behind requireRole("admin") in Register.
+func (a *authHandler) updateUserRoles(ctx *gin.Context) {
+	userID := ctx.Param("id")
+	req := &updateUserRolesReq{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		a.logger.Warn("Warn: invalid request body")
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	switch req.Action {
+	case "grant":
+		err = a.repo.GrantRole(userID, req.Role)
+	case "revoke":
+		err = a.repo.RevokeRole(userID, req.Role)
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "action must be \"grant\" or \"revoke\""})
+		return
+	}
+	if err != nil {
+		a.logger.Error("failed to update user roles", zap.String("user", userID), zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update roles"})
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
@@ -1,43 +1,69 @@
 package routes
 
 import (
+	"Recommendation-System/external/email"
+	"Recommendation-System/password"
 	repo "Recommendation-System/repository"
 	schema "Recommendation-System/repository/schema"
 	"Recommendation-System/token"
-	"bytes"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strings"
 	"time"
 
+	"crypto/rand"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler interface {
 	login(ctx *gin.Context)
 	register(ctx *gin.Context)
 	refresh(ctx *gin.Context)
+	logout(ctx *gin.Context)
+	enrollTwoFactor(ctx *gin.Context)
+	verifyTwoFactor(ctx *gin.Context)
+	twoFactorLogin(ctx *gin.Context)
+	forgotPassword(ctx *gin.Context)
+	resetPassword(ctx *gin.Context)
+	updateUserRoles(ctx *gin.Context)
 }
 
 type authHandler struct {
-	repo   repo.UserRepo
-	logger *zap.Logger
+	repo        repo.UserRepo
+	tokenStore  repo.TokenStore
+	rateLimiter repo.RateLimiter
+	emailSender email.Sender
+	logger      *zap.Logger
 }
 
-func NewAuthHandler(logger *zap.Logger, repo repo.UserRepo) AuthHandler {
+func NewAuthHandler(
+	logger *zap.Logger,
+	repo repo.UserRepo,
+	tokenStore repo.TokenStore,
+	rateLimiter repo.RateLimiter,
+	emailSender email.Sender,
+) AuthHandler {
 	return &authHandler{
-		repo:   repo,
-		logger: logger,
+		repo:        repo,
+		tokenStore:  tokenStore,
+		rateLimiter: rateLimiter,
+		emailSender: emailSender,
+		logger:      logger,
 	}
 }
 
 const _ctxKey_UserID = "userID"
 const _ctxKey_JWT = "jwt"
-const jwtExpPeriod = 7 * 24 * time.Hour
+const _ctxKey_JTI = "jti"
+const _ctxKey_JWT_EXP = "jwtExp"
+const _ctxKey_Claims = "claims"
+const accessTokenExpPeriod = 15 * time.Minute
+const refreshTokenExpPeriod = 7 * 24 * time.Hour
+const twoFactorChallengeExpPeriod = 5 * time.Minute
 const authorizationHeaderField = "Authorization"
 
 type loginReq struct {
@@ -59,36 +85,110 @@ func (a *authHandler) login(ctx *gin.Context) {
 		return
 	}
 
-	if err := checkPassword(user.Password, req.Password); err != nil {
+	ok, needsRehash, err := password.Default.Verify(user.Password, req.Password)
+	if err != nil {
+		a.logger.Error("failed to verify password hash", zap.String("user", req.Username), zap.Error(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if !ok {
 		a.logger.Warn("Warn: invalid password")
 		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
+	if needsRehash {
+		a.rehashPassword(user, req.Password)
+	}
 
-	signedToken, err := token.GenJWT(
-		user.ID,
-		user.UserName,
-		time.Now().Add(jwtExpPeriod).Unix())
+	if user.TwoFactorEnabled {
+		challenge, err := token.GenChallengeJWT(user.ID.String(), time.Now().Add(twoFactorChallengeExpPeriod).Unix())
+		if err != nil {
+			a.logger.Error("failed to sign 2fa challenge", zap.String("user", req.Username), zap.Error(err))
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"challenge": challenge})
+		return
+	}
+
+	roles, err := a.repo.SelectRolesForUser(user.ID.String())
 	if err != nil {
-		a.logger.Error("failed to sign jwt", zap.String("user", req.Username), zap.Error(err))
+		a.logger.Error("failed to load user roles", zap.String("user", req.Username), zap.Error(err))
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
 		return
 	}
+	respondWithTokenPair(ctx, a.tokenStore, a.logger, user, roles)
+}
 
+// respondWithTokenPair issues a fresh access/refresh token pair for user
+// and writes it as the login response body. Shared by every path that
+// ends in a successful login: password login, 2FA challenge completion,
+// and Google OAuth.
+func respondWithTokenPair(ctx *gin.Context, tokenStore repo.TokenStore, logger *zap.Logger, user schema.User, roles []string) {
+	accessToken, refreshToken, err := issueTokenPair(ctx, tokenStore, user, roles)
+	if err != nil {
+		logger.Error("failed to issue token pair", zap.String("user", user.UserName), zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+		return
+	}
 	ctx.JSON(http.StatusOK, gin.H{
-		"token":    signedToken,
-		"username": user.UserName,
+		"token":        accessToken,
+		"refreshToken": refreshToken,
+		"username":     user.UserName,
 	})
 }
 
-func checkPassword(storedPassword, loginPassword string) error {
-	if storedPassword == "" || loginPassword == "" {
-		return errors.New("given password(s) is empty")
+// issueTokenPair mints a fresh short-lived access token and an opaque
+// refresh token for user, storing the refresh token in tokenStore keyed
+// by its own jti so it can be looked up and rotated later.
+func issueTokenPair(ctx *gin.Context, tokenStore repo.TokenStore, user schema.User, roles []string) (accessToken, refreshToken string, err error) {
+	accessToken, err = token.GenJWT(
+		user.ID.String(),
+		user.UserName,
+		token.NewJTI(),
+		roles,
+		time.Now().Add(accessTokenExpPeriod).Unix())
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshJTI, err := newRefreshTokenJTI()
+	if err != nil {
+		return "", "", err
+	}
+	if err := tokenStore.StoreRefreshToken(ctx, refreshJTI, repo.RefreshTokenData{
+		UserID: user.ID.String(),
+		Roles:  roles,
+		Exp:    time.Now().Add(refreshTokenExpPeriod),
+	}); err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshJTI, nil
+}
+
+// newRefreshTokenJTI generates a random 256-bit, hex-encoded opaque
+// refresh token.
+func newRefreshTokenJTI() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// rehashPassword re-hashes plain with the current Argon2id parameters and
+// persists it, transparently upgrading accounts still on a legacy bcrypt
+// hash or outdated Argon2id parameters the next time they log in
+// successfully. Failures are logged but never block the login itself.
+func (a *authHandler) rehashPassword(user schema.User, plain string) {
+	newHash, err := password.Default.Hash(plain)
+	if err != nil {
+		a.logger.Error("failed to rehash password", zap.String("user", user.UserName), zap.Error(err))
+		return
+	}
+	if err := a.repo.UpdateUserPassword(user.ID.String(), newHash); err != nil {
+		a.logger.Error("failed to persist rehashed password", zap.String("user", user.UserName), zap.Error(err))
 	}
-	passwordBuf := bytes.Buffer{}
-	passwordBuf.WriteString(loginPassword)
-	passwordBuf.WriteString(repo.Salt)
-	return bcrypt.CompareHashAndPassword([]byte(storedPassword), passwordBuf.Bytes())
 }
 
 type signUpReq struct {
@@ -111,38 +211,123 @@ func (a *authHandler) register(ctx *gin.Context) {
 	json, _ := json.Marshal(req)
 	a.logger.Info("Info: user req" + string(json))
 
-	if err := a.repo.InsertUser(schema.User{
+	hashedPassword, err := password.Default.Hash(req.Password)
+	if err != nil {
+		a.logger.Error("failed to hash password", zap.String("user", req.Username), zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to insert user"})
+		return
+	}
+
+	user := schema.User{
 		UserName: req.Username,
-		Password: req.Password,
+		Password: hashedPassword,
 		Gender:   req.Gender,
 		Age:      req.Age,
 		Email:    req.Email,
 		Phone:    req.Phone,
 		Address:  req.Address,
-	}); err != nil {
+	}
+	if err := a.repo.InsertUser(user); err != nil {
 		a.logger.Error("Error: failed to insert user", zap.String("user", req.Username), zap.Error(err))
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to insert user"})
 		return
 	}
+
+	insertedUser, err := a.repo.SelectUserByUsername(req.Username)
+	if err != nil {
+		a.logger.Error("failed to load newly registered user", zap.String("user", req.Username), zap.Error(err))
+		return
+	}
+	if err := a.repo.GrantRole(insertedUser.ID.String(), "user"); err != nil {
+		a.logger.Error("failed to grant default role", zap.String("user", req.Username), zap.Error(err))
+	}
+}
+
+type refreshReq struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
 }
 
+// refresh rotates a still-valid refresh token: the old one is deleted and
+// a new one takes its place, and a fresh access token is issued alongside
+// it.
 func (a *authHandler) refresh(ctx *gin.Context) {
-	jwt, _ := ctx.Get(_ctxKey_JWT) // the token is validated by middleware
-	jwtStr, _ := jwt.(string)      // type assertion should be safe, TODO: need relevent tests added in middleware
-	newToken, err := token.RefreshJWT(jwtStr, time.Now().Add(jwtExpPeriod).Unix())
+	req := &refreshReq{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		a.logger.Warn("Warn: invalid request body")
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	data, err := a.tokenStore.GetRefreshToken(ctx, req.RefreshToken)
 	if err != nil {
-		user, _ := ctx.Get(_ctxKey_UserID)
-		userUUID, _ := user.(uuid.UUID)
-		a.logger.Warn("invalid user", zap.String("user", userUUID.String()), zap.Error(err))
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "token generation failed"})
+		a.logger.Warn("Warn: invalid refresh token", zap.Error(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
+	user, err := a.repo.SelectUserByID(data.UserID)
+	if err != nil {
+		a.logger.Warn("Warn: refresh token user not found", zap.Error(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if err := a.tokenStore.DeleteRefreshToken(ctx, req.RefreshToken); err != nil {
+		a.logger.Error("failed to revoke old refresh token", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+		return
+	}
+	// Re-fetch roles rather than trusting data.Roles: a role granted or
+	// revoked since the old refresh token was issued must take effect on
+	// the next refresh, not just on the next full login.
+	roles, err := a.repo.SelectRolesForUser(user.ID.String())
+	if err != nil {
+		a.logger.Error("failed to load user roles", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+		return
+	}
+	accessToken, refreshToken, err := issueTokenPair(ctx, a.tokenStore, user, roles)
+	if err != nil {
+		a.logger.Error("failed to issue token pair", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
-		"token": newToken,
+		"token":        accessToken,
+		"refreshToken": refreshToken,
 	})
 }
 
-func authenticate(repo repo.UserRepo, logger *zap.Logger) gin.HandlerFunc {
+// logout revokes the caller's refresh token and blacklists the access
+// token presented on this request so neither can be reused.
+func (a *authHandler) logout(ctx *gin.Context) {
+	req := &refreshReq{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		a.logger.Warn("Warn: invalid request body")
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if err := a.tokenStore.DeleteRefreshToken(ctx, req.RefreshToken); err != nil {
+		a.logger.Error("failed to revoke refresh token", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+		return
+	}
+
+	claims, _ := ctx.Get(_ctxKey_JTI)
+	jti, _ := claims.(string)
+	exp, _ := ctx.Get(_ctxKey_JWT_EXP)
+	expTime, _ := exp.(time.Time)
+	if jti != "" {
+		if err := a.tokenStore.BlacklistAccessToken(ctx, jti, time.Until(expTime)); err != nil {
+			a.logger.Error("failed to blacklist access token", zap.Error(err))
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "logout failed"})
+			return
+		}
+	}
+	ctx.Status(http.StatusOK)
+}
+
+func authenticate(repo repo.UserRepo, tokenStore repo.TokenStore, logger *zap.Logger) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		auth := ctx.Request.Header.Get(authorizationHeaderField)
 		prefix := "Bearer "
@@ -174,6 +359,48 @@ func authenticate(repo repo.UserRepo, logger *zap.Logger) gin.HandlerFunc {
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
+		if userClaims.TwoFactorPending {
+			logger.Warn("Warn: 2fa challenge token used as access token")
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		if blacklisted, err := tokenStore.IsBlacklisted(ctx, userClaims.JTI); err != nil {
+			logger.Error("failed to check token blacklist", zap.Error(err))
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		} else if blacklisted {
+			logger.Warn("Warn: token has been revoked")
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
 		ctx.Set(_ctxKey_UserID, userClaims.UserID)
+		ctx.Set(_ctxKey_JWT, tokenStr)
+		ctx.Set(_ctxKey_JTI, userClaims.JTI)
+		ctx.Set(_ctxKey_JWT_EXP, userClaims.ExpiresAt.Time)
+		ctx.Set(_ctxKey_Claims, userClaims)
+	}
+}
+
+// requireRole builds middleware that rejects the request with 403 unless
+// the caller's access token (already validated by authenticate) carries
+// at least one of roles. Must be chained after authenticate.
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		raw, ok := ctx.Get(_ctxKey_Claims)
+		if !ok {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		claims, ok := raw.(*token.Claims)
+		if !ok {
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		for _, role := range roles {
+			if claims.HasRole(role) {
+				return
+			}
+		}
+		ctx.AbortWithStatus(http.StatusForbidden)
 	}
 }
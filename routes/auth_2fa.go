@@ -0,0 +1,295 @@
+package routes
+
+import (
+	schema "Recommendation-System/repository/schema"
+	"Recommendation-System/token"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/skip2/go-qrcode"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const totpIssuer = "Recommendation-System"
+const recoveryCodeCount = 10
+
+// totpEncryptionKey is the AES-256 key TOTP secrets are encrypted with at
+// rest. It is set at startup by SetTOTPEncryptionKey; "encrypted at
+// rest" with a key checked into source control protects against
+// nothing.
+var totpEncryptionKey []byte
+
+// SetTOTPEncryptionKey installs the AES-256 key used to encrypt and
+// decrypt TOTP secrets at rest. Callers should set this once at
+// startup, e.g. Register wiring it from envconfig.
+func SetTOTPEncryptionKey(key []byte) {
+	totpEncryptionKey = key
+}
+
+type enrollTwoFactorResp struct {
+	OTPAuthURL    string   `json:"otpauthUrl"`
+	QRCodePNGB64  string   `json:"qrCodePngBase64"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// enrollTwoFactor generates a new TOTP secret and recovery codes for the
+// already-authenticated caller. TwoFactorEnabled is not flipped on until
+// verifyTwoFactor confirms the user can produce a valid code.
+func (a *authHandler) enrollTwoFactor(ctx *gin.Context) {
+	userID, _ := ctx.Get(_ctxKey_UserID)
+	userIDStr, _ := userID.(string)
+	user, err := a.repo.SelectUserByID(userIDStr)
+	if err != nil {
+		a.logger.Warn("Warn: user not found", zap.Error(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: user.UserName,
+	})
+	if err != nil {
+		a.logger.Error("failed to generate totp secret", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "2fa enrollment failed"})
+		return
+	}
+
+	encryptedSecret, err := encryptTOTPSecret(key.Secret())
+	if err != nil {
+		a.logger.Error("failed to encrypt totp secret", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "2fa enrollment failed"})
+		return
+	}
+
+	plainCodes, hashedCodes, err := genRecoveryCodes(user.ID)
+	if err != nil {
+		a.logger.Error("failed to generate recovery codes", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "2fa enrollment failed"})
+		return
+	}
+
+	if err := a.repo.EnrollTwoFactor(userIDStr, encryptedSecret, hashedCodes); err != nil {
+		a.logger.Error("failed to save 2fa enrollment", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "2fa enrollment failed"})
+		return
+	}
+
+	png, err := qrcode.Encode(key.URL(), qrcode.Medium, 256)
+	if err != nil {
+		a.logger.Error("failed to render totp qr code", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "2fa enrollment failed"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTwoFactorResp{
+		OTPAuthURL:    key.URL(),
+		QRCodePNGB64:  base64.StdEncoding.EncodeToString(png),
+		RecoveryCodes: plainCodes,
+	})
+}
+
+type verifyTwoFactorReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// verifyTwoFactor confirms the caller can produce a valid code from the
+// secret generated by enrollTwoFactor, and enables 2FA on success.
+func (a *authHandler) verifyTwoFactor(ctx *gin.Context) {
+	req := &verifyTwoFactorReq{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		a.logger.Warn("Warn: invalid request body")
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := ctx.Get(_ctxKey_UserID)
+	userIDStr, _ := userID.(string)
+	user, err := a.repo.SelectUserByID(userIDStr)
+	if err != nil {
+		a.logger.Warn("Warn: user not found", zap.Error(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if !a.validateTOTPCode(user, req.Code) {
+		a.logger.Warn("Warn: invalid totp code")
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	if err := a.repo.ConfirmTwoFactor(userIDStr); err != nil {
+		a.logger.Error("failed to confirm 2fa enrollment", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "2fa verification failed"})
+		return
+	}
+	ctx.Status(http.StatusOK)
+}
+
+type twoFactorLoginReq struct {
+	Challenge    string `json:"challenge" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// twoFactorLogin exchanges a pending-2FA challenge token plus a TOTP code
+// (or a recovery code) for a real access/refresh token pair.
+func (a *authHandler) twoFactorLogin(ctx *gin.Context) {
+	req := &twoFactorLoginReq{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		a.logger.Warn("Warn: invalid request body")
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	claims, err := token.ParseJWT(req.Challenge)
+	if err != nil || !claims.TwoFactorPending {
+		a.logger.Warn("Warn: invalid 2fa challenge")
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := a.repo.SelectUserByID(claims.UserID)
+	if err != nil {
+		a.logger.Warn("Warn: user not found", zap.Error(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case req.Code != "":
+		if !a.validateTOTPCode(user, req.Code) {
+			a.logger.Warn("Warn: invalid totp code")
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	case req.RecoveryCode != "":
+		if !a.consumeRecoveryCode(user, req.RecoveryCode) {
+			a.logger.Warn("Warn: invalid recovery code")
+			ctx.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	default:
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	roles, err := a.repo.SelectRolesForUser(user.ID.String())
+	if err != nil {
+		a.logger.Error("failed to load user roles", zap.String("user", user.UserName), zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "token generation failed"})
+		return
+	}
+	respondWithTokenPair(ctx, a.tokenStore, a.logger, user, roles)
+}
+
+// validateTOTPCode decrypts user's stored secret and checks code against
+// it, allowing a ±1 step (30s) window.
+func (a *authHandler) validateTOTPCode(user schema.User, code string) bool {
+	secret, err := decryptTOTPSecret(user.TOTPSecret)
+	if err != nil {
+		a.logger.Error("failed to decrypt totp secret", zap.Error(err))
+		return false
+	}
+	valid, err := totp.ValidateCustom(code, secret, time.Now(), totp.ValidateOpts{
+		Period:    30,
+		Skew:      1,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	return err == nil && valid
+}
+
+// consumeRecoveryCode checks code against user's unused recovery codes
+// and marks the matching one used.
+func (a *authHandler) consumeRecoveryCode(user schema.User, code string) bool {
+	codes, err := a.repo.SelectRecoveryCodes(user.ID.String())
+	if err != nil {
+		a.logger.Error("failed to load recovery codes", zap.Error(err))
+		return false
+	}
+	for _, c := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(c.CodeHash), []byte(code)) == nil {
+			if err := a.repo.MarkRecoveryCodeUsed(c.ID); err != nil {
+				a.logger.Error("failed to consume recovery code", zap.Error(err))
+				return false
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// genRecoveryCodes returns recoveryCodeCount freshly generated codes in
+// both plaintext (shown to the user once) and bcrypt-hashed form (what
+// gets persisted).
+func genRecoveryCodes(userID uuid.UUID) (plain []string, hashed []schema.RecoveryCode, err error) {
+	plain = make([]string, recoveryCodeCount)
+	hashed = make([]schema.RecoveryCode, recoveryCodeCount)
+	for i := range plain {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(buf)
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		plain[i] = code
+		hashed[i] = schema.RecoveryCode{UserID: userID, CodeHash: string(hash)}
+	}
+	return plain, hashed, nil
+}
+
+func encryptTOTPSecret(secret string) (string, error) {
+	block, err := aes.NewCipher(totpEncryptionKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	ciphertext := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return hex.EncodeToString(ciphertext), nil
+}
+
+func decryptTOTPSecret(encoded string) (string, error) {
+	ciphertext, err := hex.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(totpEncryptionKey)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return "", errors.New("routes: totp secret ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
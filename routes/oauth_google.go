@@ -0,0 +1,216 @@
+package routes
+
+import (
+	"Recommendation-System/password"
+	repo "Recommendation-System/repository"
+	schema "Recommendation-System/repository/schema"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// GoogleOAuthConfig holds the client credentials registered with Google
+// for the "Sign in with Google" flow.
+type GoogleOAuthConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+type googleOAuthHandler struct {
+	repo       repo.UserRepo
+	tokenStore repo.TokenStore
+	stateStore repo.OAuthStateStore
+	oauthConf  *oauth2.Config
+	provider   *oidc.Provider
+	verifier   *oidc.IDTokenVerifier
+	logger     *zap.Logger
+}
+
+// NewGoogleOAuthHandler builds the handler for /api/auth/oauth/google/*.
+// It eagerly fetches Google's OIDC discovery document so JWKS rotation is
+// handled by the oidc package for the lifetime of the process.
+func NewGoogleOAuthHandler(
+	ctx context.Context,
+	logger *zap.Logger,
+	repo repo.UserRepo,
+	tokenStore repo.TokenStore,
+	stateStore repo.OAuthStateStore,
+	cfg GoogleOAuthConfig,
+) (GoogleOAuthHandler, error) {
+	provider, err := oidc.NewProvider(ctx, "https://accounts.google.com")
+	if err != nil {
+		return nil, err
+	}
+	return &googleOAuthHandler{
+		repo:       repo,
+		tokenStore: tokenStore,
+		stateStore: stateStore,
+		provider:   provider,
+		verifier:   provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		oauthConf: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     google.Endpoint,
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		logger: logger,
+	}, nil
+}
+
+// GoogleOAuthHandler is the Google OAuth login surface, kept separate from
+// AuthHandler since it needs its own constructor (it talks to Google's
+// discovery document at startup).
+type GoogleOAuthHandler interface {
+	start(ctx *gin.Context)
+	callback(ctx *gin.Context)
+}
+
+// start redirects the browser to Google's consent screen, binding a
+// random state value to the caller's IP so the callback can reject
+// forged or replayed redirects.
+func (h *googleOAuthHandler) start(ctx *gin.Context) {
+	state, err := newOAuthState()
+	if err != nil {
+		h.logger.Error("failed to generate oauth state", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "oauth start failed"})
+		return
+	}
+	if err := h.stateStore.SaveState(ctx, state, ctx.ClientIP()); err != nil {
+		h.logger.Error("failed to save oauth state", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "oauth start failed"})
+		return
+	}
+	ctx.Redirect(http.StatusFound, h.oauthConf.AuthCodeURL(state))
+}
+
+// callback exchanges the authorization code for tokens, validates the ID
+// token, and matches its claims against repo.UserRepo, issuing the
+// caller's normal access/refresh token pair on success.
+func (h *googleOAuthHandler) callback(ctx *gin.Context) {
+	state := ctx.Query("state")
+	code := ctx.Query("code")
+	if state == "" || code == "" {
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	ok, err := h.stateStore.ConsumeState(ctx, state, ctx.ClientIP())
+	if err != nil {
+		h.logger.Error("failed to check oauth state", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "oauth callback failed"})
+		return
+	}
+	if !ok {
+		h.logger.Warn("Warn: invalid or replayed oauth state")
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	oauthToken, err := h.oauthConf.Exchange(ctx, code)
+	if err != nil {
+		h.logger.Warn("Warn: failed to exchange oauth code", zap.Error(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	rawIDToken, ok := oauthToken.Extra("id_token").(string)
+	if !ok {
+		h.logger.Warn("Warn: oauth token response missing id_token")
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	idToken, err := h.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		h.logger.Warn("Warn: failed to verify google id token", zap.Error(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Sub           string `json:"sub"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		h.logger.Warn("Warn: failed to parse google id token claims", zap.Error(err))
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	if !claims.EmailVerified {
+		h.logger.Warn("Warn: google id token email is not verified")
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.resolveUser(claims.Sub, claims.Email)
+	if err != nil {
+		h.logger.Error("failed to resolve oauth user", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "oauth callback failed"})
+		return
+	}
+
+	roles, err := h.repo.SelectRolesForUser(user.ID.String())
+	if err != nil {
+		h.logger.Error("failed to load user roles", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "oauth callback failed"})
+		return
+	}
+	respondWithTokenPair(ctx, h.tokenStore, h.logger, user, roles)
+}
+
+// resolveUser matches sub/email against existing accounts, linking or
+// auto-provisioning one if needed.
+func (h *googleOAuthHandler) resolveUser(sub, email string) (schema.User, error) {
+	if user, err := h.repo.SelectUserByOIDCSubject(sub); err == nil {
+		return user, nil
+	}
+
+	if user, err := h.repo.SelectUserByEmail(email); err == nil {
+		if err := h.repo.LinkOIDCSubject(user.ID.String(), sub); err != nil {
+			return schema.User{}, err
+		}
+		user.OIDCSubject = &sub
+		return user, nil
+	}
+
+	randomPassword, err := newOAuthState()
+	if err != nil {
+		return schema.User{}, err
+	}
+	hashed, err := password.Default.Hash(randomPassword)
+	if err != nil {
+		return schema.User{}, err
+	}
+	user := schema.User{
+		UserName:    email,
+		Password:    hashed,
+		Email:       email,
+		OIDCSubject: &sub,
+	}
+	if err := h.repo.InsertUser(user); err != nil {
+		return schema.User{}, err
+	}
+	insertedUser, err := h.repo.SelectUserByOIDCSubject(sub)
+	if err != nil {
+		return schema.User{}, err
+	}
+	if err := h.repo.GrantRole(insertedUser.ID.String(), "user"); err != nil {
+		h.logger.Error("failed to grant default role", zap.String("email", email), zap.Error(err))
+	}
+	return insertedUser, nil
+}
+
+func newOAuthState() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
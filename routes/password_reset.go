@@ -0,0 +1,141 @@
+package routes
+
+import (
+	"Recommendation-System/password"
+	schema "Recommendation-System/repository/schema"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const passwordResetExpPeriod = 30 * time.Minute
+const passwordResetRateLimit = 5
+const passwordResetRateLimitWindow = time.Hour
+
+type forgotPasswordReq struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// forgotPassword issues a single-use reset token for req.Email and emails
+// it to the user. Past the body check and the per-IP+email rate limit
+// (429 when exceeded), it responds 200 whether or not the email belongs
+// to an account, so callers cannot use it to enumerate users.
+func (a *authHandler) forgotPassword(ctx *gin.Context) {
+	req := &forgotPasswordReq{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		a.logger.Warn("Warn: invalid request body")
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	rateLimitKey := ctx.ClientIP() + ":" + req.Email
+	if allowed, err := a.rateLimiter.Allow(ctx, rateLimitKey, passwordResetRateLimit, passwordResetRateLimitWindow); err != nil {
+		a.logger.Error("failed to check password reset rate limit", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "request failed"})
+		return
+	} else if !allowed {
+		ctx.AbortWithStatus(http.StatusTooManyRequests)
+		return
+	}
+
+	user, err := a.repo.SelectUserByEmail(req.Email)
+	if err != nil {
+		a.logger.Warn("Warn: password reset requested for unknown email")
+		ctx.Status(http.StatusOK)
+		return
+	}
+
+	resetToken, tokenHash, err := newPasswordResetToken()
+	if err != nil {
+		a.logger.Error("failed to generate password reset token", zap.Error(err))
+		ctx.Status(http.StatusOK)
+		return
+	}
+	if err := a.repo.InsertPasswordReset(schema.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(passwordResetExpPeriod),
+	}); err != nil {
+		a.logger.Error("failed to save password reset token", zap.Error(err))
+		ctx.Status(http.StatusOK)
+		return
+	}
+
+	body := fmt.Sprintf("Use the code below to reset your password. It expires in 30 minutes.\n\n%s", resetToken)
+	if err := a.emailSender.Send(user.Email, "Reset your password", body); err != nil {
+		a.logger.Error("failed to send password reset email", zap.String("user", user.UserName), zap.Error(err))
+	}
+	ctx.Status(http.StatusOK)
+}
+
+type resetPasswordReq struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// resetPassword consumes a reset token minted by forgotPassword, sets a
+// new password, and revokes every existing session for the user.
+func (a *authHandler) resetPassword(ctx *gin.Context) {
+	req := &resetPasswordReq{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		a.logger.Warn("Warn: invalid request body")
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	reset, err := a.repo.SelectPasswordResetByTokenHash(hashPasswordResetToken(req.Token))
+	if err != nil {
+		a.logger.Warn("Warn: invalid password reset token")
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	if time.Now().After(reset.ExpiresAt) {
+		a.logger.Warn("Warn: expired password reset token")
+		ctx.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	hashed, err := password.Default.Hash(req.NewPassword)
+	if err != nil {
+		a.logger.Error("failed to hash new password", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "password reset failed"})
+		return
+	}
+
+	userID := reset.UserID.String()
+	if err := a.repo.UpdateUserPassword(userID, hashed); err != nil {
+		a.logger.Error("failed to update password", zap.Error(err))
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "password reset failed"})
+		return
+	}
+	if err := a.repo.MarkPasswordResetUsed(reset.ID); err != nil {
+		a.logger.Error("failed to consume password reset token", zap.Error(err))
+	}
+	if err := a.tokenStore.DeleteAllRefreshTokensForUser(ctx, userID); err != nil {
+		a.logger.Error("failed to revoke sessions after password reset", zap.Error(err))
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// newPasswordResetToken generates a random 32-byte reset token, returning
+// both the plaintext (emailed to the user) and its SHA-256 hash (what
+// gets persisted).
+func newPasswordResetToken() (plainToken, tokenHash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	plainToken = hex.EncodeToString(buf)
+	return plainToken, hashPasswordResetToken(plainToken), nil
+}
+
+func hashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
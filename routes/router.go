@@ -3,10 +3,14 @@ package routes
 import (
 	"Recommendation-System/envconfig"
 	api "Recommendation-System/external/api"
+	"Recommendation-System/external/email"
 	"Recommendation-System/repository"
+	"Recommendation-System/token"
+	"context"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
 )
 
@@ -21,23 +25,58 @@ func Register(
 	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
 	router.Use(cors.New(config))
 
+	token.SetSecretKey([]byte(env.JWT_SECRET))
+	SetTOTPEncryptionKey([]byte(env.TOTP_ENCRYPTION_KEY))
+
 	// Create Repo instances
 	userRepo := repository.NewUserRepo(db)
+	redisClient := redis.NewClient(&redis.Options{Addr: env.REDIS_ADDR})
+	tokenStore := repository.NewRedisTokenStore(redisClient)
+	rateLimiter := repository.NewRedisRateLimiter(redisClient)
+	emailSender := email.NewSMTPSender(email.SMTPConfig{
+		Host:     env.SMTP_HOST,
+		Port:     env.SMTP_PORT,
+		Username: env.SMTP_USERNAME,
+		Password: env.SMTP_PASSWORD,
+		From:     env.SMTP_FROM,
+	})
 
 	// Register handlers for no authentication API
-	// authHandler := NewAuthHandler(logger, userRepo)
+	authHandler := NewAuthHandler(logger, userRepo, tokenStore, rateLimiter, emailSender)
 	noAuthRouters := router.Group("")
-	// noAuthRouters.POST("/api/auth/register", authHandler.register)
-	// noAuthRouters.POST("/api/auth/login", authHandler.login)
-	// noAuthRouters.POST("/api/auth/refresh", authHandler.refresh)
+	noAuthRouters.POST("/api/auth/register", authHandler.register)
+	noAuthRouters.POST("/api/auth/login", authHandler.login)
+	noAuthRouters.POST("/api/auth/refresh", authHandler.refresh)
+	noAuthRouters.POST("/api/auth/2fa/login", authHandler.twoFactorLogin)
+	noAuthRouters.POST("/api/auth/password/forgot", authHandler.forgotPassword)
+	noAuthRouters.POST("/api/auth/password/reset", authHandler.resetPassword)
+
+	authRouters := router.Group("", authenticate(userRepo, tokenStore, logger))
+	authRouters.POST("/api/auth/logout", authHandler.logout)
+	authRouters.POST("/api/auth/2fa/enroll", authHandler.enrollTwoFactor)
+	authRouters.POST("/api/auth/2fa/verify", authHandler.verifyTwoFactor)
+
+	adminRouters := router.Group("", authenticate(userRepo, tokenStore, logger), requireRole("admin"))
+	adminRouters.POST("/api/admin/users/:id/roles", authHandler.updateUserRoles)
 
-	// authRouters := router.Group("", authenticate(userRepo, logger))
+	oauthStateStore := repository.NewRedisOAuthStateStore(redisClient)
+	googleOAuthHandler, err := NewGoogleOAuthHandler(context.Background(), logger, userRepo, tokenStore, oauthStateStore, GoogleOAuthConfig{
+		ClientID:     env.GOOGLE_OAUTH_CLIENT_ID,
+		ClientSecret: env.GOOGLE_OAUTH_CLIENT_SECRET,
+		RedirectURL:  env.GOOGLE_OAUTH_REDIRECT_URL,
+	})
+	if err != nil {
+		logger.Error("failed to set up google oauth, disabling it", zap.Error(err))
+	} else {
+		noAuthRouters.GET("/api/auth/oauth/google/start", googleOAuthHandler.start)
+		noAuthRouters.GET("/api/auth/oauth/google/callback", googleOAuthHandler.callback)
+	}
 	// router group to add middle ware for authentication
 	userHandler := NewUserHandler(logger, userRepo, api.NewMapUtilities(env.GOOGLE_MAP_API_KEY), api.NewEventsSearcher(env.TICKET_MASTER_API_KEY))
 
 	noAuthRouters.GET("/api/user/events", userHandler.listEvents)
 	noAuthRouters.POST("/api/user/events/like", userHandler.likeEvent)
 	noAuthRouters.POST("/api/user/events/dislike", userHandler.dislikeEvent)
-	noAuthRouters.GET("/api/user/events/recommend", userHandler.recommendEvents)
+	authRouters.GET("/api/user/events/recommend", requireRole("user"), userHandler.recommendEvents)
 	return router
 }
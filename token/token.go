@@ -0,0 +1,109 @@
+// Package token issues and validates the JWTs used to authenticate API
+// requests.
+package token
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrJWTExpired is returned by ParseJWT when the token's exp claim has
+// passed.
+var ErrJWTExpired = errors.New("token: jwt is expired")
+
+// secretKey signs all access and 2FA challenge tokens. It is set at
+// startup by SetSecretKey and must never be a value checked into source
+// control — anyone who can read this repo could otherwise forge a token
+// with arbitrary roles.
+var secretKey []byte
+
+// SetSecretKey installs the key used to sign and verify JWTs. Callers
+// should set this once at startup, e.g. Register wiring it from
+// envconfig before any request is served.
+func SetSecretKey(key []byte) {
+	secretKey = key
+}
+
+// Claims is the JWT payload carried by access and 2FA challenge tokens.
+type Claims struct {
+	UserID   string `json:"userID"`
+	UserName string `json:"userName"`
+	// JTI uniquely identifies this access token so it can be blacklisted
+	// on logout independent of its expiry.
+	JTI string `json:"jti"`
+	// TwoFactorPending is set on the short-lived challenge token returned
+	// by login when the account has 2FA enabled; it is never set on a
+	// real access token.
+	TwoFactorPending bool `json:"2fa_pending,omitempty"`
+	// Roles are the role names granted to the user as of issuance, used
+	// by the requireRole middleware. Stale until the token is refreshed.
+	Roles []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// HasRole reports whether c carries role.
+func (c *Claims) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// GenJWT signs a new access token for userID/userName/roles, expiring at
+// exp (unix seconds). jti should be a fresh random identifier (see
+// NewJTI); it is echoed back by ParseJWT so callers can blacklist the
+// token.
+func GenJWT(userID, userName, jti string, roles []string, exp int64) (string, error) {
+	return signClaims(Claims{
+		UserID:   userID,
+		UserName: userName,
+		JTI:      jti,
+		Roles:    roles,
+	}, exp)
+}
+
+// GenChallengeJWT signs a short-lived token for userID marking that
+// login is blocked pending a 2FA code, per /api/auth/2fa/login.
+func GenChallengeJWT(userID string, exp int64) (string, error) {
+	return signClaims(Claims{
+		UserID:           userID,
+		TwoFactorPending: true,
+	}, exp)
+}
+
+func signClaims(claims Claims, exp int64) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Unix(exp, 0)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secretKey)
+}
+
+// ParseJWT validates tokenStr and returns its claims.
+func ParseJWT(tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		return secretKey, nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrJWTExpired
+		}
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("token: invalid jwt")
+	}
+	return claims, nil
+}
+
+// NewJTI generates a fresh, random token identifier suitable for the JTI
+// claim.
+func NewJTI() string {
+	return uuid.NewString()
+}